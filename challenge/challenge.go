@@ -0,0 +1,443 @@
+// Package challenge implements DNS-01 challenge provisioning against the
+// pluggable providers/dns registry. DnsChallenge is reusable as the solver
+// for both the certbot manual-hook protocol (see RunChallenger) and the
+// native ACME client in cmd/acme.
+package challenge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nijave/certbot-manual-freedns/providers/dns"
+	// Providers self-register via init(); import the ones we ship for
+	// their side effect. Add a new provider by importing its package here.
+	_ "github.com/nijave/certbot-manual-freedns/providers/dns/cloudflare"
+	_ "github.com/nijave/certbot-manual-freedns/providers/dns/freedns"
+)
+
+// FreeTtlValue Value taken from the FreeDNS website form
+const FreeTtlValue = "For our premium supporters"
+
+const DeleteRecordNotFoundMessage = "couldn't find record to delete"
+
+// DefaultDnsProvider is used when CERTBOT_DNS_PROVIDER isn't set, keeping
+// this program's original FreeDNS-only behavior as the default.
+const DefaultDnsProvider = "freedns"
+
+type DnsHost = dns.Provider
+
+// CnameResolver is used to follow _acme-challenge CNAME delegation.
+type CnameResolver interface {
+	LookupCNAME(context.Context, string) (string, error)
+}
+
+type DnsChallenge struct {
+	ChallengeDomain string
+	ChallengeValue  string
+	Log             *zap.SugaredLogger
+	LastZoneId      string
+	LastRecordName  string
+	LastRecordFQDN  string
+	// CnameOverride lets a challenge domain be treated as delegated via
+	// CNAME to another FQDN even before that CNAME is published, keyed and
+	// valued by FQDN without a trailing dot. See parseCnameOverride.
+	CnameOverride map[string]string
+	// NameserverOverride, if set, is polled directly in waitForPropagation
+	// instead of discovering the zone's authoritative nameservers -- handy
+	// for tests and split-horizon setups.
+	NameserverOverride []string
+	// PreviousChallengeValue, if set, is also swept by Create's pre-publish
+	// cleanup alongside ChallengeValue. It's meant for a prior run's TXT
+	// record left behind because its cleanup hook never ran (e.g. a crash
+	// between Create and Delete) -- reaping it by name alone would risk
+	// deleting an unrelated concurrent sibling's record (a wildcard and its
+	// base domain validating at the same time), so the stale value has to be
+	// named explicitly instead.
+	PreviousChallengeValue string
+	dnsHost                DnsHost
+	resolver               CnameResolver
+	nsResolver             NsResolver
+	// Timeout is the overall deadline for a single Create or Delete call.
+	Timeout time.Duration
+	// ResolveTimeout bounds each individual DNS query (CNAME lookup, SOA/NS
+	// walk, or TXT poll) -- defaulted to 3s by ensureResolver if unset.
+	ResolveTimeout time.Duration
+	// RetryTimeout is how long waitForPropagation waits between polling
+	// attempts -- defaulted to 10s by ensureResolver if unset.
+	RetryTimeout time.Duration
+	ctx          context.Context
+	// authoritativeServers caches the result of the first nameserver
+	// discovery for this challenge so waitForPropagation's retry loop
+	// doesn't redo the SOA/NS walk on every attempt -- the authoritative
+	// set for a zone doesn't change mid-validation.
+	authoritativeServers []string
+}
+
+// parseCnameOverride parses CERTBOT_DNS_CNAME_OVERRIDE, a comma separated
+// list of challengeFqdn=targetFqdn pairs, e.g.
+// "_acme-challenge.example.com=_acme-challenge.delegate.example.net".
+func parseCnameOverride(value string) map[string]string {
+	override := map[string]string{}
+	if value == "" {
+		return override
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		override[strings.TrimSuffix(parts[0], ".")] = strings.TrimSuffix(parts[1], ".")
+	}
+
+	return override
+}
+
+func getZoneFor(zones []string, domain string) string {
+	zone := ""
+	for _, z := range zones {
+		if strings.HasSuffix(domain, z) && len(z) >= len(zone) {
+			zone = z
+		}
+	}
+	return zone
+}
+
+func (c *DnsChallenge) ensureResolver() {
+	if c.ResolveTimeout == 0 {
+		c.ResolveTimeout = 3 * time.Second
+	}
+
+	if c.RetryTimeout == 0 {
+		c.RetryTimeout = 10 * time.Second
+	}
+
+	if c.resolver == nil {
+		c.resolver = &net.Resolver{PreferGo: true}
+	}
+
+	if c.nsResolver == nil {
+		c.nsResolver = &dnsResolver{
+			RecursiveServer: DefaultRecursiveNameserver,
+			OverrideServers: c.NameserverOverride,
+		}
+	}
+}
+
+// resolveChallengeFQDN returns the FQDN the TXT challenge record should
+// actually be published to and polled at. If _acme-challenge.<ChallengeDomain>
+// is CNAMEd elsewhere (or overridden via c.CnameOverride), that target is
+// used instead -- this is the "dns challenge delegation" pattern that lets
+// a domain delegate its ACME challenges to a zone dedicated to automation.
+func (c *DnsChallenge) resolveChallengeFQDN() (string, error) {
+	challengeName := "_acme-challenge." + c.ChallengeDomain
+
+	if override, ok := c.CnameOverride[challengeName]; ok {
+		c.Log.Infow("using cname override", "name", challengeName, "target", override)
+		return override, nil
+	}
+
+	timeout, timeoutCancel := context.WithTimeout(c.ctx, c.ResolveTimeout)
+	defer timeoutCancel()
+	target, err := c.resolver.LookupCNAME(timeout, challengeName)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "no such host") {
+			return challengeName, nil
+		}
+		return "", err
+	}
+
+	target = strings.TrimSuffix(target, ".")
+	if target == "" || target == challengeName {
+		return challengeName, nil
+	}
+
+	c.Log.Infow("following cname delegation", "name", challengeName, "target", target)
+	return target, nil
+}
+
+func (c *DnsChallenge) setup() error {
+	var ctx context.Context
+	if c.Timeout > 0 {
+		var ctxCancel func()
+		ctx, ctxCancel = context.WithTimeout(context.Background(), c.Timeout)
+		defer ctxCancel()
+	} else {
+		ctx = context.Background()
+	}
+	c.ctx = ctx
+	c.authoritativeServers = nil
+
+	c.ensureResolver()
+
+	recordFQDN, err := c.resolveChallengeFQDN()
+	if err != nil {
+		return err
+	}
+
+	domains, _, err := c.dnsHost.GetDomains()
+	if err != nil {
+		return err
+	}
+
+	// TODO not sure if these are used correctly...
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+
+	zones := make([]string, len(domains))
+	for domain := range domains {
+		zones = append(zones, domain)
+	}
+
+	challengeDomainZone := getZoneFor(zones, recordFQDN)
+	if challengeDomainZone == "" {
+		return errors.New("couldn't find zone for domain")
+	}
+	c.LastZoneId = domains[challengeDomainZone]
+	c.Log.Infow("found zone", "zoneName", challengeDomainZone, "zoneId", c.LastZoneId)
+
+	c.LastRecordName = strings.TrimSuffix(recordFQDN, "."+challengeDomainZone)
+	c.LastRecordFQDN = recordFQDN
+
+	return nil
+}
+
+// authoritativeNameservers discovers the zone's authoritative nameservers
+// on the first call and caches them for the rest of this challenge's
+// retries -- the authoritative set for a zone doesn't change mid-validation,
+// so rediscovering it on every checkPropagation call would just be extra
+// SOA/NS round trips against the same recursive resolver.
+func (c *DnsChallenge) authoritativeNameservers() ([]string, error) {
+	if c.authoritativeServers != nil {
+		return c.authoritativeServers, nil
+	}
+
+	nsCtx, nsCancel := context.WithTimeout(c.ctx, c.ResolveTimeout)
+	defer nsCancel()
+	servers, err := c.nsResolver.LookupAuthoritativeNameservers(nsCtx, c.LastRecordFQDN)
+	if err != nil {
+		return nil, err
+	}
+
+	c.authoritativeServers = servers
+	return servers, nil
+}
+
+// checkPropagation queries every one of the zone's authoritative
+// nameservers concurrently, succeeding only once all agree on the expected
+// TXT value. Polling every authoritative server (rather than a single
+// hardcoded one) avoids the classic case where certbot fails because one
+// nameserver hasn't caught up yet.
+func (c *DnsChallenge) checkPropagation() error {
+	servers, err := c.authoritativeNameservers()
+	if err != nil {
+		return err
+	}
+
+	results := make(chan error, len(servers))
+	for _, server := range servers {
+		server := server
+		go func() {
+			timeout, cancel := context.WithTimeout(c.ctx, c.ResolveTimeout)
+			defer cancel()
+
+			records, err := c.nsResolver.LookupTXTAt(timeout, server, c.LastRecordFQDN)
+			if err != nil {
+				results <- fmt.Errorf("%s: %w", server, err)
+				return
+			}
+			// Wildcard + base domain issuance needs two TXT values to
+			// coexist at the same name, so success just means ours is
+			// somewhere in the set, not that it's the only one.
+			for _, record := range records {
+				if record == c.ChallengeValue {
+					results <- nil
+					return
+				}
+			}
+			results <- fmt.Errorf("%s: expected txt value not found yet, got %v", server, records)
+		}()
+	}
+
+	var lastErr error
+	for range servers {
+		if err := <-results; err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func (c *DnsChallenge) waitForPropagation() error {
+	// Seems like this usually takes ~50 seconds
+	for i := 0; i < 30; i++ {
+		if err := c.checkPropagation(); err == nil {
+			c.Log.Infow("txt value propagated to all nameservers", "record", c.LastRecordFQDN, "try", i)
+			return nil
+		} else {
+			c.Log.Warnw("txt record not yet propagated everywhere", "record", c.LastRecordFQDN, "try", i, "error", err)
+		}
+
+		// https://stackoverflow.com/a/69291047/2751619
+		timer := time.NewTimer(c.RetryTimeout)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return c.ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return errors.New("timed out waiting for txt record")
+}
+
+func (c *DnsChallenge) Create() error {
+	err := c.setup()
+	if err != nil {
+		return err
+	}
+
+	err = c.deleteValues(c.ChallengeValue, c.PreviousChallengeValue)
+	if err != nil && err.Error() != DeleteRecordNotFoundMessage {
+		return err
+	}
+
+	c.Log.Infow("creating dns challenge", "name", c.LastRecordName, "value", c.ChallengeValue)
+
+	err = c.dnsHost.CreateRecord(c.LastZoneId, c.LastRecordName, "TXT", c.ChallengeValue, FreeTtlValue)
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+
+	// It doesn't seem like certbot will wait around--it will insta-fail if there's NXDOMAIN
+	// Try to find the record first before returning to certbot
+	err = c.waitForPropagation()
+
+	return err
+}
+
+// Delete removes the TXT record holding c.ChallengeValue at LastRecordFQDN.
+// It only ever touches records whose value matches the challenge being
+// cleaned up, so sibling TXT values -- e.g. a wildcard and its base domain
+// validating concurrently -- are left alone.
+func (c *DnsChallenge) Delete() error {
+	return c.deleteValues(c.ChallengeValue)
+}
+
+// deleteValues removes every TXT record at LastRecordFQDN whose value is one
+// of values (blank values are ignored), leaving any other value -- a
+// concurrent sibling's, or an unrelated stale one nobody named -- untouched.
+func (c *DnsChallenge) deleteValues(values ...string) error {
+	records, err := c.dnsHost.GetRecords(c.LastZoneId)
+	if err != nil {
+		return err
+	}
+
+	recordIds, ok := c.dnsHost.FindRecordIds(records, c.LastRecordFQDN)
+	if !ok {
+		return errors.New(DeleteRecordNotFoundMessage)
+	}
+
+	match := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value != "" {
+			match[value] = true
+		}
+	}
+
+	var staleIds []string
+	for _, recordId := range recordIds {
+		if match[records[recordId].Value] {
+			staleIds = append(staleIds, recordId)
+		}
+	}
+	c.Log.Infow("found records to delete", "recordIds", staleIds)
+	if len(staleIds) == 0 {
+		return errors.New(DeleteRecordNotFoundMessage)
+	}
+
+	for _, recordId := range staleIds {
+		err = c.dnsHost.DeleteRecord(recordId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// New builds a DnsChallenge wired to the provider named by
+// CERTBOT_DNS_PROVIDER (falling back to DefaultDnsProvider) and configured
+// with CERTBOT_DNS_CNAME_OVERRIDE and CERTBOT_DNS_PREVIOUS_VALUE -- the same
+// setup RunChallenger and cmd/acme's solver both need.
+func New(challengeDomain, challengeValue string, sugar *zap.SugaredLogger) (*DnsChallenge, error) {
+	providerName := os.Getenv("CERTBOT_DNS_PROVIDER")
+	if providerName == "" {
+		providerName = DefaultDnsProvider
+	}
+
+	provider, err := dns.NewDNSProviderByName(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DnsChallenge{
+		ChallengeDomain:        challengeDomain,
+		ChallengeValue:         challengeValue,
+		Log:                    sugar,
+		dnsHost:                provider,
+		CnameOverride:          parseCnameOverride(os.Getenv("CERTBOT_DNS_CNAME_OVERRIDE")),
+		PreviousChallengeValue: os.Getenv("CERTBOT_DNS_PREVIOUS_VALUE"),
+	}, nil
+}
+
+// RunChallenger implements certbot's manual-auth-hook/manual-cleanup-hook
+// protocol: on the auth hook invocation (authScriptOutput empty) it creates
+// the challenge record and prints "zoneId,recordFQDN" to stdout; certbot
+// feeds that back as CERTBOT_AUTH_OUTPUT on the cleanup hook invocation, which
+// this deletes.
+func RunChallenger(challengeDomain, recordValue, authScriptOutput string, sugar *zap.SugaredLogger) error {
+	challenger, err := New(challengeDomain, recordValue, sugar)
+	if err != nil {
+		return err
+	}
+
+	if authScriptOutput == "" {
+		err = challenger.Create()
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write([]byte(fmt.Sprintf("%s,%s", challenger.LastZoneId, challenger.LastRecordFQDN)))
+		sugar.Info("challenge created")
+	} else {
+		lastRunInfo := strings.Split(authScriptOutput, ",")
+		if len(lastRunInfo) != 2 {
+			return errors.New("expected CERTBOT_AUTH_OUTPUT to be 2 comma separated values: zoneId,recordFQDN")
+		}
+		challenger.LastZoneId = lastRunInfo[0]
+		challenger.LastRecordFQDN = lastRunInfo[1]
+		err = challenger.Delete()
+		if err != nil {
+			return err
+		}
+		sugar.Info("challenge deleted")
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// DefaultRecursiveNameserver is used to walk up a domain's labels looking
+// for its SOA record and for the NS query against the apex it finds. The
+// NS hostnames that query returns are not resolved through it.
+const DefaultRecursiveNameserver = "1.1.1.1:53"
+
+// NsResolver discovers the nameservers authoritative for a name and can
+// query a specific one of them directly. It exists as its own interface
+// (rather than folding straight into waitForPropagation) so tests can
+// simulate nameserver disagreement without hitting the network.
+type NsResolver interface {
+	LookupAuthoritativeNameservers(ctx context.Context, fqdn string) ([]string, error)
+	LookupTXTAt(ctx context.Context, server, fqdn string) ([]string, error)
+}
+
+// dnsResolver is the real NsResolver, backed by github.com/miekg/dns.
+type dnsResolver struct {
+	// RecursiveServer is queried for the SOA/NS walk used to discover the
+	// authoritative servers.
+	RecursiveServer string
+	// OverrideServers, if non-empty, is returned directly from
+	// LookupAuthoritativeNameservers instead of performing discovery --
+	// populated from DnsChallenge.NameserverOverride.
+	OverrideServers []string
+}
+
+func (r *dnsResolver) LookupAuthoritativeNameservers(ctx context.Context, fqdn string) ([]string, error) {
+	if len(r.OverrideServers) > 0 {
+		return r.OverrideServers, nil
+	}
+
+	client := &miekgdns.Client{}
+
+	apex, err := findZoneApex(ctx, client, fqdn, r.RecursiveServer)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := new(miekgdns.Msg).SetQuestion(apex, miekgdns.TypeNS)
+	resp, _, err := client.ExchangeContext(ctx, msg, r.RecursiveServer)
+	if err != nil {
+		return nil, fmt.Errorf("NS query for %s: %w", apex, err)
+	}
+
+	var servers []string
+	for _, rr := range resp.Answer {
+		if ns, ok := rr.(*miekgdns.NS); ok {
+			servers = append(servers, strings.TrimSuffix(ns.Ns, ".")+":53")
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no NS records found for zone %s", apex)
+	}
+
+	return servers, nil
+}
+
+func (r *dnsResolver) LookupTXTAt(ctx context.Context, server, fqdn string) ([]string, error) {
+	client := &miekgdns.Client{}
+	msg := new(miekgdns.Msg).SetQuestion(miekgdns.Fqdn(fqdn), miekgdns.TypeTXT)
+	resp, _, err := client.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, rr := range resp.Answer {
+		if txt, ok := rr.(*miekgdns.TXT); ok {
+			values = append(values, strings.Join(txt.Txt, ""))
+		}
+	}
+	return values, nil
+}
+
+// findZoneApex returns the fully qualified, dot-terminated name that owns
+// fqdn's SOA record, trying fqdn itself and then each parent label in
+// turn -- the same zone-cut walk lego's dns01 package does before asking
+// for NS records.
+func findZoneApex(ctx context.Context, client *miekgdns.Client, fqdn, recursiveServer string) (string, error) {
+	name := miekgdns.Fqdn(fqdn)
+
+	for {
+		msg := new(miekgdns.Msg).SetQuestion(name, miekgdns.TypeSOA)
+		resp, _, err := client.ExchangeContext(ctx, msg, recursiveServer)
+		if err != nil {
+			return "", fmt.Errorf("SOA query for %s: %w", name, err)
+		}
+
+		for _, rr := range resp.Answer {
+			if soa, ok := rr.(*miekgdns.SOA); ok {
+				return soa.Hdr.Name, nil
+			}
+		}
+
+		labels := miekgdns.SplitDomainName(name)
+		if len(labels) <= 1 {
+			return "", errors.New("couldn't find SOA record walking up " + fqdn)
+		}
+		name = miekgdns.Fqdn(strings.Join(labels[1:], "."))
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/acme"
+)
+
+// loadOrCreateAccountKey reads an ECDSA P-256 account key from path, or
+// generates and persists a new one if no file exists there yet. The same
+// key is used across runs so registering with the CA is idempotent --
+// RFC 8555 CAs return the existing account for a key they've already seen.
+func loadOrCreateAccountKey(path string) (*ecdsa.PrivateKey, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: not a PEM file", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, pemBytes, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// registerAccount builds an acme.Client using the given key and registers
+// (or re-confirms) an account for it, accepting the CA's terms of service
+// automatically.
+func registerAccount(ctx context.Context, directoryURL, email string, key *ecdsa.PrivateKey) (*acme.Client, error) {
+	client := &acme.Client{Key: key, DirectoryURL: directoryURL}
+
+	var contact []string
+	if email != "" {
+		contact = []string{"mailto:" + email}
+	}
+
+	_, err := client.Register(ctx, &acme.Account{Contact: contact}, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("registering account: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// newCertificateRequest generates a fresh ECDSA P-256 key and a CSR for the
+// given SANs (the first of which becomes the subject CommonName), wildcards
+// included -- x509.CreateCertificateRequest doesn't care whether a DNS name
+// starts with "*.".
+func newCertificateRequest(domains []string) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, csr, nil
+}
+
+// writeCertificate writes fullchain.pem (the leaf certificate followed by
+// the chain, in the order returned by the CA) and privkey.pem to outputDir.
+func writeCertificate(outputDir string, chain [][]byte, key *ecdsa.PrivateKey) error {
+	var fullchain []byte
+	for _, der := range chain {
+		fullchain = append(fullchain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "fullchain.pem"), fullchain, 0644); err != nil {
+		return fmt.Errorf("writing fullchain.pem: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("marshaling private key: %w", err)
+	}
+	privkey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(outputDir, "privkey.pem"), privkey, 0600); err != nil {
+		return fmt.Errorf("writing privkey.pem: %w", err)
+	}
+
+	return nil
+}
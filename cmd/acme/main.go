@@ -0,0 +1,159 @@
+// Command acme is a lego-style ACME client: it drives a full order
+// (registration, DNS-01 validation and finalization) against an ACME
+// directory using challenge.DnsChallenge as the solver, so certificates can
+// be issued and renewed without certbot or Python. The root
+// certbot-manual-freedns binary and its CERTBOT_AUTH_OUTPUT protocol keep
+// working unchanged for anyone who'd rather keep using certbot.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+
+	"github.com/nijave/certbot-manual-freedns/challenge"
+)
+
+const stagingDirectoryURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+func main() {
+	domains := flag.String("domains", "", "comma separated list of domains to request a certificate for (the first is used as the CSR subject); supports wildcards")
+	email := flag.String("email", "", "contact email to register with the CA")
+	directoryURL := flag.String("directory-url", acme.LetsEncryptURL, "ACME directory URL")
+	staging := flag.Bool("staging", false, "use the Let's Encrypt staging directory instead of -directory-url")
+	accountKeyPath := flag.String("account-key", "acme-account.pem", "path to persist the account's private key")
+	outputDir := flag.String("out", ".", "directory to write fullchain.pem and privkey.pem to")
+	timeout := flag.Duration("timeout", 5*time.Minute, "overall deadline for completing a single dns-01 challenge")
+	resolveTimeout := flag.Duration("resolve-timeout", 3*time.Second, "timeout for each individual DNS query issued while checking propagation")
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+	sugar := logger.Sugar()
+
+	if *domains == "" {
+		panic("-domains is required")
+	}
+	domainList := strings.Split(*domains, ",")
+
+	if *staging {
+		*directoryURL = stagingDirectoryURL
+	}
+
+	ctx := context.Background()
+
+	key, err := loadOrCreateAccountKey(*accountKeyPath)
+	if err != nil {
+		panic(fmt.Errorf("loading account key: %w", err))
+	}
+
+	client, err := registerAccount(ctx, *directoryURL, *email, key)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := issueCertificate(ctx, client, domainList, *timeout, *resolveTimeout, *outputDir, sugar); err != nil {
+		panic(err)
+	}
+}
+
+// issueCertificate drives an order from authorization through to a written
+// certificate, using DnsChallenge (the same solver RunChallenger's certbot
+// hook protocol uses) to satisfy every dns-01 challenge the CA asks for.
+func issueCertificate(ctx context.Context, client *acme.Client, domains []string, timeout, resolveTimeout time.Duration, outputDir string, sugar *zap.SugaredLogger) error {
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return fmt.Errorf("authorizing order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return fmt.Errorf("fetching authorization: %w", err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		if err := completeDNS01(ctx, client, authz, timeout, resolveTimeout, sugar); err != nil {
+			return fmt.Errorf("%s: %w", authz.Identifier.Value, err)
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("waiting for order: %w", err)
+	}
+
+	certKey, csr, err := newCertificateRequest(domains)
+	if err != nil {
+		return fmt.Errorf("generating CSR: %w", err)
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("finalizing order: %w", err)
+	}
+
+	if err := writeCertificate(outputDir, chain, certKey); err != nil {
+		return err
+	}
+
+	sugar.Infow("certificate issued", "domains", domains, "outputDir", outputDir)
+	return nil
+}
+
+// completeDNS01 finds the dns-01 challenge in authz, provisions and tears
+// down the TXT record via a DnsChallenge, and waits for the CA to mark the
+// authorization valid.
+func completeDNS01(ctx context.Context, client *acme.Client, authz *acme.Authorization, timeout, resolveTimeout time.Duration, sugar *zap.SugaredLogger) error {
+	var dns01 *acme.Challenge
+	for _, chal := range authz.Challenges {
+		if chal.Type == "dns-01" {
+			dns01 = chal
+			break
+		}
+	}
+	if dns01 == nil {
+		return fmt.Errorf("no dns-01 challenge offered")
+	}
+
+	keyAuth, err := client.DNS01ChallengeRecord(dns01.Token)
+	if err != nil {
+		return fmt.Errorf("computing key authorization: %w", err)
+	}
+
+	solver, err := challenge.New(authz.Identifier.Value, keyAuth, sugar)
+	if err != nil {
+		return fmt.Errorf("building dns solver: %w", err)
+	}
+	solver.Timeout = timeout
+	solver.ResolveTimeout = resolveTimeout
+
+	if err := solver.Create(); err != nil {
+		return fmt.Errorf("provisioning dns-01 record: %w", err)
+	}
+	defer func() {
+		if err := solver.Delete(); err != nil {
+			sugar.Warnw("failed to clean up dns-01 record", "domain", authz.Identifier.Value, "error", err)
+		}
+	}()
+
+	if _, err := client.Accept(ctx, dns01); err != nil {
+		return fmt.Errorf("accepting challenge: %w", err)
+	}
+
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("waiting for authorization: %w", err)
+	}
+
+	return nil
+}
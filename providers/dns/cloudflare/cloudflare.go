@@ -0,0 +1,208 @@
+// Package cloudflare implements providers/dns.Provider against the
+// Cloudflare API (https://api.cloudflare.com/client/v4) and self-registers
+// as "cloudflare". See providers/dns/freedns for the original provider.
+package cloudflare
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/nijave/certbot-manual-freedns/providers/dns"
+)
+
+// apiBase is a var (not a const) so tests can point it at an httptest.Server.
+var apiBase = "https://api.cloudflare.com/client/v4"
+
+// perPage is the page size requested for paginated endpoints (/zones,
+// /zones/:id/dns_records). Cloudflare paginates both by default, so
+// accounts/zones with more than one page worth of results would otherwise
+// be silently truncated.
+const perPage = 100
+
+// resultInfo is Cloudflare's pagination envelope, present on list endpoints.
+type resultInfo struct {
+	Page       int `json:"page"`
+	TotalPages int `json:"total_pages"`
+}
+
+func init() {
+	dns.Register("cloudflare", New)
+}
+
+// Config is read from the environment with the CLOUDFLARE_ prefix, e.g.
+// CLOUDFLARE_API_TOKEN.
+type Config struct {
+	ApiToken string `envconfig:"API_TOKEN" required:"true"`
+}
+
+type Provider struct {
+	config Config
+	client *http.Client
+	// lastZoneId is remembered from the most recent GetRecords call since
+	// the Provider interface's DeleteRecord only takes a record id, but
+	// Cloudflare's API scopes record deletion under a zone.
+	lastZoneId string
+}
+
+func New() (dns.Provider, error) {
+	var config Config
+	if err := envconfig.Process("cloudflare", &config); err != nil {
+		return nil, err
+	}
+	return &Provider{config: config, client: http.DefaultClient}, nil
+}
+
+func (p *Provider) do(method, path string, body interface{}, out interface{}) (resultInfo, error) {
+	var reqBody bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return resultInfo{}, err
+		}
+		reqBody = *bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, apiBase+path, &reqBody)
+	if err != nil {
+		return resultInfo{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.ApiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return resultInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result     json.RawMessage `json:"result"`
+		ResultInfo resultInfo      `json:"result_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return resultInfo{}, err
+	}
+	if !envelope.Success {
+		if len(envelope.Errors) > 0 {
+			return resultInfo{}, errors.New(envelope.Errors[0].Message)
+		}
+		return resultInfo{}, fmt.Errorf("cloudflare API request failed with status %s", resp.Status)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return resultInfo{}, err
+		}
+	}
+	return envelope.ResultInfo, nil
+}
+
+// doPaginated calls page(1), page(2), ... until result_info reports there
+// are no more pages. Each call is expected to issue a request with that
+// page number and fold its results into the caller's accumulator.
+func (p *Provider) doPaginated(page func(pageNum int) (resultInfo, error)) error {
+	for pageNum := 1; ; pageNum++ {
+		info, err := page(pageNum)
+		if err != nil {
+			return err
+		}
+		if info.TotalPages == 0 || pageNum >= info.TotalPages {
+			return nil
+		}
+	}
+}
+
+func (p *Provider) GetDomains() (map[string]string, map[string]string, error) {
+	type zoneDTO struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	nameToId := map[string]string{}
+	idToName := map[string]string{}
+
+	err := p.doPaginated(func(pageNum int) (resultInfo, error) {
+		var zones []zoneDTO
+		info, err := p.do(http.MethodGet, fmt.Sprintf("/zones?page=%d&per_page=%d", pageNum, perPage), nil, &zones)
+		if err != nil {
+			return resultInfo{}, err
+		}
+		for _, zone := range zones {
+			nameToId[zone.Name] = zone.Id
+			idToName[zone.Id] = zone.Name
+		}
+		return info, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nameToId, idToName, nil
+}
+
+func (p *Provider) GetRecords(zoneId string) (map[string]dns.Record, error) {
+	type recordDTO struct {
+		Id      string `json:"id"`
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+	}
+
+	result := map[string]dns.Record{}
+
+	err := p.doPaginated(func(pageNum int) (resultInfo, error) {
+		var records []recordDTO
+		info, err := p.do(http.MethodGet, fmt.Sprintf("/zones/%s/dns_records?page=%d&per_page=%d", zoneId, pageNum, perPage), nil, &records)
+		if err != nil {
+			return resultInfo{}, err
+		}
+		for _, record := range records {
+			result[record.Id] = dns.Record{
+				Id:    record.Id,
+				Name:  record.Name,
+				Type:  record.Type,
+				Value: record.Content,
+			}
+		}
+		return info, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.lastZoneId = zoneId
+
+	return result, nil
+}
+
+func (p *Provider) FindRecordIds(records map[string]dns.Record, name string) ([]string, bool) {
+	var ids []string
+	for id, record := range records {
+		if record.Name == name {
+			ids = append(ids, id)
+		}
+	}
+	return ids, len(ids) > 0
+}
+
+func (p *Provider) CreateRecord(zoneId, name, recordType, value, ttl string) error {
+	_, err := p.do(http.MethodPost, "/zones/"+zoneId+"/dns_records", map[string]string{
+		"type":    recordType,
+		"name":    name,
+		"content": value,
+	}, nil)
+	return err
+}
+
+func (p *Provider) DeleteRecord(recordId string) error {
+	_, err := p.do(http.MethodDelete, "/zones/"+p.lastZoneId+"/dns_records/"+recordId, nil, nil)
+	return err
+}
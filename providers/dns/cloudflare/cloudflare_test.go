@@ -0,0 +1,83 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateRecordDoesNotQuoteValue guards against FreeDNS's quoting quirk
+// (see providers/dns/freedns) leaking into this provider: Cloudflare's API
+// takes and returns TXT content verbatim, so a value round-tripped through
+// CreateRecord/GetRecords must come back byte-for-byte identical, with no
+// quotes added or stripped.
+func TestCreateRecordDoesNotQuoteValue(t *testing.T) {
+	const zoneId = "zone1"
+	const recordId = "record1"
+	const recordName = "_acme-challenge.example.com"
+	const value = "abc123"
+
+	var createdContent string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/"+zoneId+"/dns_records":
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var payload struct {
+				Type    string `json:"type"`
+				Name    string `json:"name"`
+				Content string `json:"content"`
+			}
+			require.NoError(t, json.Unmarshal(body, &payload))
+			createdContent = payload.Content
+
+			writeEnvelope(t, w, map[string]string{"id": recordId}, resultInfo{})
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/"+zoneId+"/dns_records":
+			writeEnvelope(t, w, []map[string]string{{
+				"id":      recordId,
+				"name":    recordName,
+				"type":    "TXT",
+				"content": createdContent,
+			}}, resultInfo{Page: 1, TotalPages: 1})
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	provider := &Provider{config: Config{ApiToken: "token"}, client: server.Client()}
+	restoreApiBase := setApiBase(server.URL)
+	defer restoreApiBase()
+
+	err := provider.CreateRecord(zoneId, recordName, "TXT", value, "")
+	require.NoError(t, err)
+	assert.Equal(t, value, createdContent, "CreateRecord must post the TXT value unquoted")
+
+	records, err := provider.GetRecords(zoneId)
+	require.NoError(t, err)
+	assert.Equal(t, value, records[recordId].Value, "GetRecords must return the TXT value unquoted")
+}
+
+// setApiBase points apiBase at a test server and returns a func restoring it.
+func setApiBase(url string) func() {
+	original := apiBase
+	apiBase = url
+	return func() { apiBase = original }
+}
+
+func writeEnvelope(t *testing.T, w http.ResponseWriter, result interface{}, info resultInfo) {
+	t.Helper()
+	envelope := map[string]interface{}{
+		"success":     true,
+		"errors":      []interface{}{},
+		"result":      result,
+		"result_info": info,
+	}
+	require.NoError(t, json.NewEncoder(w).Encode(envelope))
+}
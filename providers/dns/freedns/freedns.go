@@ -0,0 +1,96 @@
+// Package freedns adapts github.com/ramalhais/go-freedns to the
+// providers/dns.Provider interface and self-registers as "freedns".
+package freedns
+
+import (
+	"fmt"
+	"strings"
+
+	gofreedns "github.com/ramalhais/go-freedns"
+
+	"github.com/nijave/certbot-manual-freedns/providers/dns"
+)
+
+func init() {
+	dns.Register("freedns", New)
+}
+
+type Provider struct {
+	client *gofreedns.FreeDNS
+}
+
+// New builds a Provider, reading AUTH_LOGIN/AUTH_PASSWORD (or
+// AUTH_COOKIE_NAME/AUTH_COOKIE_VALUE) from the environment via
+// go-freedns's own ConfigEnv.
+func New() (dns.Provider, error) {
+	client, err := gofreedns.NewFreeDNS()
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{client: client}, nil
+}
+
+func (p *Provider) GetDomains() (map[string]string, map[string]string, error) {
+	return p.client.GetDomains()
+}
+
+func (p *Provider) GetRecords(zoneId string) (map[string]dns.Record, error) {
+	records, err := p.client.GetRecords(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]dns.Record, len(records))
+	for id, record := range records {
+		value := record.Value
+		if record.Type == "TXT" {
+			value = unquote(value)
+		}
+		result[id] = dns.Record{
+			Id:    record.Id,
+			Name:  record.Name,
+			Type:  record.Type,
+			Value: value,
+		}
+	}
+	return result, nil
+}
+
+func (p *Provider) FindRecordIds(records map[string]dns.Record, name string) ([]string, bool) {
+	native := make(map[string]gofreedns.Record, len(records))
+	for id, record := range records {
+		native[id] = gofreedns.Record{
+			Id:    record.Id,
+			Name:  record.Name,
+			Type:  record.Type,
+			Value: record.Value,
+		}
+	}
+	return p.client.FindRecordIds(native, name)
+}
+
+// CreateRecord quotes value before submitting it: FreeDNS's web form expects
+// a literally-quoted string for TXT records and strips the quotes itself
+// before publishing the DNS RR, but echoes the quoted form back from its own
+// records listing (see GetRecords/unquote). Every other Provider method and
+// DnsChallenge deal exclusively in the unquoted value.
+func (p *Provider) CreateRecord(zoneId, name, recordType, value, ttl string) error {
+	if recordType == "TXT" {
+		value = fmt.Sprintf("%q", value)
+	}
+	return p.client.CreateRecord(zoneId, name, recordType, value, ttl)
+}
+
+func (p *Provider) DeleteRecord(recordId string) error {
+	return p.client.DeleteRecord(recordId)
+}
+
+// unquote strips the surrounding quotes FreeDNS's records listing echoes
+// back for TXT values (added by CreateRecord). Non-TXT records, or values
+// that somehow aren't quoted, pass through unchanged.
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+// Package dns defines the pluggable interface DNS backends implement so
+// DnsChallenge can publish and remove the ACME dns-01 TXT record without
+// knowing which registrar or cloud DNS service is behind it.
+//
+// Providers self-register from their own package's init() (see
+// providers/dns/freedns and providers/dns/cloudflare for examples), so
+// adding a new backend never requires touching DnsChallenge or main.go --
+// only importing the new provider package for its init() side effect.
+// This mirrors the provider registry pattern used by lego's dns01 package.
+package dns
+
+import "fmt"
+
+// Record is a provider-agnostic view of a single DNS record.
+type Record struct {
+	Id    string
+	Name  string
+	Type  string
+	Value string
+}
+
+// Provider is implemented by each DNS backend capable of creating and
+// deleting the TXT records used by the ACME dns-01 challenge.
+type Provider interface {
+	GetDomains() (map[string]string, map[string]string, error)
+	GetRecords(zoneId string) (map[string]Record, error)
+	FindRecordIds(records map[string]Record, name string) ([]string, bool)
+	CreateRecord(zoneId, name, recordType, value, ttl string) error
+	DeleteRecord(recordId string) error
+}
+
+// Factory constructs a Provider, typically reading its credentials from
+// the environment.
+type Factory func() (Provider, error)
+
+var registry = map[string]Factory{}
+
+// Register adds a Factory under name. Provider packages call this from
+// init() so importing a provider package (even just for its side effect)
+// makes it available to NewDNSProviderByName.
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// NewDNSProviderByName builds the Provider registered under name, which
+// is normally read from the CERTBOT_DNS_PROVIDER environment variable.
+func NewDNSProviderByName(name string) (Provider, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider %q", name)
+	}
+	return factory()
+}